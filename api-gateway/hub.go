@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Event is a single pub/sub message delivered to WebSocket subscribers.
+type Event struct {
+	Topic   string      `json:"topic"`
+	Type    string      `json:"type"`
+	Payload interface{} `json:"payload"`
+}
+
+// Hub is a small in-process pub/sub broker. Subscribers register for a
+// topic (e.g. "news:*" or "comments:42") and receive every Event published
+// to that exact topic. It does no wildcard matching itself - "news:*" is
+// just the topic string the news aggregator publishes to.
+type Hub struct {
+	mu          sync.RWMutex
+	subscribers map[string]map[chan Event]struct{}
+}
+
+// NewHub creates an empty Hub ready for use.
+func NewHub() *Hub {
+	return &Hub{
+		subscribers: make(map[string]map[chan Event]struct{}),
+	}
+}
+
+// Subscribe registers a new channel for topic and returns it along with an
+// unsubscribe func that must be called when the subscriber is done.
+func (h *Hub) Subscribe(topic string) (chan Event, func()) {
+	ch := make(chan Event, 16)
+
+	h.mu.Lock()
+	if h.subscribers[topic] == nil {
+		h.subscribers[topic] = make(map[chan Event]struct{})
+	}
+	h.subscribers[topic][ch] = struct{}{}
+	h.mu.Unlock()
+
+	unsubscribe := func() {
+		h.mu.Lock()
+		delete(h.subscribers[topic], ch)
+		if len(h.subscribers[topic]) == 0 {
+			delete(h.subscribers, topic)
+		}
+		h.mu.Unlock()
+		close(ch)
+	}
+
+	return ch, unsubscribe
+}
+
+// Publish fans out an event to every subscriber of topic. A subscriber whose
+// buffer is full misses this event rather than blocking the publisher; it
+// stays subscribed and receives later events once it has room.
+func (h *Hub) Publish(topic, eventType string, payload interface{}) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for ch := range h.subscribers[topic] {
+		select {
+		case ch <- Event{Topic: topic, Type: eventType, Payload: payload}:
+		default:
+			// Subscriber isn't keeping up; skip it instead of blocking Publish.
+		}
+	}
+}
+
+// CommentsTopic returns the topic a given news article's comments are
+// published under.
+func CommentsTopic(newsID int) string {
+	return fmt.Sprintf("comments:%d", newsID)
+}
+
+// NewsTopic is the topic the aggregator polling goroutine publishes new
+// articles to.
+const NewsTopic = "news:*"
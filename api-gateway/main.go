@@ -8,11 +8,23 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
 
-	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/ilin69mark-hub/APIGateway/internal/httpclient"
+	"github.com/ilin69mark-hub/APIGateway/internal/logging"
+	"github.com/ilin69mark-hub/APIGateway/internal/middleware"
+)
+
+// Shared resilient clients for the gateway's two upstream services. Created
+// once so their circuit breakers track failures across all requests.
+var (
+	commentServiceClient = httpclient.New("comment-service")
+	censorServiceClient  = httpclient.New("censor-service")
 )
 
 // Data models
@@ -45,64 +57,37 @@ type NewsResponse struct {
 	Pagination Pagination          `json:"pagination"`
 }
 
-// Middleware for request_id and logging
-func loggingMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		start := time.Now()
-		requestID := r.Header.Get("X-Request-ID")
-		if requestID == "" {
-			requestID = uuid.New().String()
-		}
-		
-		// Log the request
-		log.Printf("[%s] [INFO] [%s] [%s] [%s] [%s] -", 
-			start.Format("2006-01-02 15:04:05"), 
-			requestID, 
-			r.RemoteAddr, 
-			r.Method, 
-			r.URL.Path)
-		
-		// Add request_id to context
-		ctx := context.WithValue(r.Context(), "request_id", requestID)
-		next.ServeHTTP(w, r.WithContext(ctx))
-		
-		// Log the response
-		duration := time.Since(start)
-		log.Printf("[%s] [INFO] [%s] [%s] [%s] [%s] [200] [%v]", 
-			start.Format("2006-01-02 15:04:05"), 
-			requestID, 
-			r.RemoteAddr, 
-			r.Method, 
-			r.URL.Path,
-			duration)
-	})
-}
-
 func getRequestParam(r *http.Request, key string) string {
 	return r.URL.Query().Get(key)
 }
 
-func getRequestID(r *http.Request) string {
-	if requestID, ok := r.Context().Value("request_id").(string); ok {
-		return requestID
-	}
-	return ""
-}
-
 func main() {
 	// Create HTTP multiplexer
 	mux := http.NewServeMux()
 
+	hub := NewHub()
+	wsTracker := newConnTracker()
+
+	limiterCfg := middleware.ConfigFromEnv()
+	if limiterCfg.LongRunningRequestRE == nil {
+		limiterCfg.LongRunningRequestRE = regexp.MustCompile(`^/ws$`)
+	}
+	limiter := middleware.NewLimiter(limiterCfg)
+
 	// Register handlers with middleware
 	mux.HandleFunc("/news", func(w http.ResponseWriter, r *http.Request) {
-		loggingMiddleware(http.HandlerFunc(newsHandler)).ServeHTTP(w, r)
+		limiter.Middleware(logging.Middleware(http.HandlerFunc(newsHandler))).ServeHTTP(w, r)
 	})
 	mux.HandleFunc("/news/", func(w http.ResponseWriter, r *http.Request) {
-		loggingMiddleware(http.HandlerFunc(newsDetailHandler)).ServeHTTP(w, r)
+		limiter.Middleware(logging.Middleware(http.HandlerFunc(newsDetailHandler))).ServeHTTP(w, r)
 	})
 	mux.HandleFunc("/comment", func(w http.ResponseWriter, r *http.Request) {
-		loggingMiddleware(http.HandlerFunc(commentHandler)).ServeHTTP(w, r)
+		limiter.Middleware(logging.Middleware(commentHandler(hub))).ServeHTTP(w, r)
+	})
+	mux.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
+		limiter.Middleware(logging.Middleware(wsHandler(hub, wsTracker))).ServeHTTP(w, r)
 	})
+	mux.Handle("/metrics", promhttp.Handler())
 
 	// Create server
 	server := &http.Server{
@@ -110,6 +95,10 @@ func main() {
 		Handler: mux,
 	}
 
+	// Start the aggregator poller that feeds news.created events into the hub.
+	pollCtx, stopPolling := context.WithCancel(context.Background())
+	go pollNewsAggregator(pollCtx, hub, 5*time.Second)
+
 	// Start server in a goroutine
 	go func() {
 		log.Printf("[*] HTTP server is started on localhost:8080")
@@ -124,6 +113,13 @@ func main() {
 	<-quit
 	log.Printf("[*] HTTP server has been stopped. Reason: interrupt")
 
+	// Stop feeding new events, then close every open /ws connection
+	// ourselves: once a connection is hijacked, net/http no longer tracks
+	// it, so server.Shutdown alone would never see it and would return
+	// immediately without waiting for it to drain.
+	stopPolling()
+	wsTracker.closeAll()
+
 	// Shutdown server gracefully
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -134,6 +130,27 @@ func main() {
 	}
 }
 
+// fetchLatestNews returns the set of known news articles. In a real
+// implementation this would call the news aggregator service; both
+// newsHandler and the /ws poller share it so polling sees the same items
+// a client would get from GET /news.
+func fetchLatestNews() []NewsShortDetailed {
+	return []NewsShortDetailed{
+		{
+			ID:      1,
+			Title:   "First News",
+			Content: "This is the content of the first news article",
+			PubTime: time.Now().Add(-24 * time.Hour),
+		},
+		{
+			ID:      2,
+			Title:   "Second News",
+			Content: "This is the content of the second news article",
+			PubTime: time.Now().Add(-12 * time.Hour),
+		},
+	}
+}
+
 func newsHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -153,22 +170,7 @@ func newsHandler(w http.ResponseWriter, r *http.Request) {
 		searchQuery = getRequestParam(r, "search") // support both parameter names
 	}
 
-	// In a real implementation, this would call the news aggregator service
-	// For now, we'll return mock data
-	news := []NewsShortDetailed{
-		{
-			ID:      1,
-			Title:   "First News",
-			Content: "This is the content of the first news article",
-			PubTime: time.Now().Add(-24 * time.Hour),
-		},
-		{
-			ID:      2,
-			Title:   "Second News",
-			Content: "This is the content of the second news article",
-			PubTime: time.Now().Add(-12 * time.Hour),
-		},
-	}
+	news := fetchLatestNews()
 
 	// Apply search filter if query is provided
 	if searchQuery != "" {
@@ -229,7 +231,7 @@ func newsDetailHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get comments for this news from CommentService
-	comments, err := getCommentsForNews(newsID, getRequestID(r))
+	comments, err := getCommentsForNews(r.Context(), newsID)
 	if err != nil {
 		log.Printf("Error fetching comments for news %d: %v", newsID, err)
 		// Continue with empty comments array
@@ -245,99 +247,93 @@ func newsDetailHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(detailedNews)
 }
 
-func getCommentsForNews(newsID int, requestID string) ([]Comment, error) {
-	// Create HTTP request to CommentService to get comments for news
-	client := &http.Client{Timeout: 10 * time.Second}
-	
-	// Build the URL to get comments for specific news
-	url := fmt.Sprintf("http://localhost:8081/comments?news_id=%d", newsID)
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, err
-	}
-	
-	// Add request ID header
-	req.Header.Set("X-Request-ID", requestID)
-	
-	// Make the request
-	resp, err := client.Do(req)
+func getCommentsForNews(ctx context.Context, newsID int) ([]Comment, error) {
+	start := time.Now()
+	defer func() { logging.RecordUpstream(ctx, "comments", time.Since(start)) }()
+
+	// format=flat avoids walking CommentService's default nested tree shape
+	// just to flatten it back out here.
+	url := fmt.Sprintf("http://localhost:8081/comments?news_id=%d&format=flat", newsID)
+	resp, err := commentServiceClient.Do(ctx, httpclient.Request{Method: http.MethodGet, URL: url})
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
-	
+
 	// Check response status
 	if resp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("comment service returned status: %d", resp.StatusCode)
 	}
-	
+
 	// Decode the response
-	var comments []Comment
-	if err := json.NewDecoder(resp.Body).Decode(&comments); err != nil {
+	var body struct {
+		Comments []Comment `json:"comments"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
 		return nil, err
 	}
-	
-	return comments, nil
+
+	return body.Comments, nil
 }
 
-func commentHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
+// commentHandler returns the /comment handler bound to hub so it can publish
+// comment.created/comment.rejected events alongside the existing
+// censor-then-save pipeline.
+func commentHandler(hub *Hub) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
 
-	var comment Comment
-	if err := json.NewDecoder(r.Body).Decode(&comment); err != nil {
-		http.Error(w, "Invalid JSON", http.StatusBadRequest)
-		return
-	}
+		var comment Comment
+		if err := json.NewDecoder(r.Body).Decode(&comment); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
 
-	// Send comment text to CensorService for validation
-	requestID := getRequestID(r)
-	if err := validateCommentWithCensorService(comment.Text, requestID); err != nil {
-		// If censorship fails, return error to client
-		http.Error(w, "Comment contains prohibited content", http.StatusBadRequest)
-		return
-	}
+		// Send comment text to CensorService for validation
+		if err := validateCommentWithCensorService(r.Context(), comment.Text); err != nil {
+			// If censorship fails, return error to client
+			hub.Publish(CommentsTopic(comment.NewsID), "comment.rejected", comment)
+			http.Error(w, "Comment contains prohibited content", http.StatusBadRequest)
+			return
+		}
 
-	// If censorship passes, save comment to CommentService
-	if err := saveCommentToService(comment, requestID); err != nil {
-		http.Error(w, "Failed to save comment", http.StatusInternalServerError)
-		return
-	}
+		// If censorship passes, save comment to CommentService
+		id, err := saveCommentToService(r.Context(), comment)
+		if err != nil {
+			http.Error(w, "Failed to save comment", http.StatusInternalServerError)
+			return
+		}
+		comment.ID = id
 
-	// Return success response
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"message": "Comment created successfully",
-		"id":      1, // In a real implementation, this would be the actual ID
-	})
-}
+		hub.Publish(CommentsTopic(comment.NewsID), "comment.created", comment)
 
-func validateCommentWithCensorService(text, requestID string) error {
-	// Create the request payload
-	payload := map[string]string{
-		"text": text,
-	}
-	
-	jsonData, err := json.Marshal(payload)
-	if err != nil {
-		return err
+		// Return success response
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "Comment created successfully",
+			"id":      id,
+		})
 	}
+}
+
+func validateCommentWithCensorService(ctx context.Context, text string) error {
+	start := time.Now()
+	defer func() { logging.RecordUpstream(ctx, "censor", time.Since(start)) }()
 
-	// Create HTTP request to CensorService
-	req, err := http.NewRequest("POST", "http://localhost:8082/check", strings.NewReader(string(jsonData)))
+	jsonData, err := json.Marshal(map[string]string{"text": text})
 	if err != nil {
 		return err
 	}
-	
-	// Add request ID header
-	req.Header.Set("X-Request-ID", requestID)
-	req.Header.Set("Content-Type", "application/json")
-
-	// Make the request
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Do(req)
+
+	resp, err := censorServiceClient.Do(ctx, httpclient.Request{
+		Method:  http.MethodPost,
+		URL:     "http://localhost:8082/check",
+		Body:    jsonData,
+		Headers: http.Header{"Content-Type": []string{"application/json"}},
+	})
 	if err != nil {
 		return err
 	}
@@ -353,41 +349,43 @@ func validateCommentWithCensorService(text, requestID string) error {
 	return nil
 }
 
-func saveCommentToService(comment Comment, requestID string) error {
-	// Create the request payload
-	payload := map[string]interface{}{
+// saveCommentToService persists comment via CommentService and returns the
+// real, store-assigned ID so the gateway doesn't have to fake one.
+func saveCommentToService(ctx context.Context, comment Comment) (int, error) {
+	start := time.Now()
+	defer func() { logging.RecordUpstream(ctx, "comments", time.Since(start)) }()
+
+	jsonData, err := json.Marshal(map[string]interface{}{
 		"news_id":   comment.NewsID,
 		"parent_id": comment.ParentID,
 		"text":      comment.Text,
-	}
-	
-	jsonData, err := json.Marshal(payload)
+	})
 	if err != nil {
-		return err
+		return 0, err
 	}
 
-	// Create HTTP request to CommentService
-	req, err := http.NewRequest("POST", "http://localhost:8081/comments", strings.NewReader(string(jsonData)))
-	if err != nil {
-		return err
-	}
-	
-	// Add request ID header
-	req.Header.Set("X-Request-ID", requestID)
-	req.Header.Set("Content-Type", "application/json")
-
-	// Make the request
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Do(req)
+	resp, err := commentServiceClient.Do(ctx, httpclient.Request{
+		Method:  http.MethodPost,
+		URL:     "http://localhost:8081/comments",
+		Body:    jsonData,
+		Headers: http.Header{"Content-Type": []string{"application/json"}},
+	})
 	if err != nil {
-		return err
+		return 0, err
 	}
 	defer resp.Body.Close()
 
 	// Check response status
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		return fmt.Errorf("comment service returned status: %d", resp.StatusCode)
+		return 0, fmt.Errorf("comment service returned status: %d", resp.StatusCode)
 	}
 
-	return nil
-}
\ No newline at end of file
+	var body struct {
+		ID int `json:"id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return 0, err
+	}
+
+	return body.ID, nil
+}
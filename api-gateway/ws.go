@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/ilin69mark-hub/APIGateway/internal/logging"
+)
+
+const (
+	wsWriteWait  = 10 * time.Second
+	wsPongWait   = 60 * time.Second
+	wsPingPeriod = (wsPongWait * 9) / 10
+)
+
+// connTracker records every live /ws connection so main can close them
+// explicitly during shutdown. Once wsUpgrader.Upgrade hijacks a connection,
+// net/http stops tracking it, so server.Shutdown has no way to reach it on
+// its own - closing here is what actually unblocks wsHandler's loop.
+type connTracker struct {
+	mu    sync.Mutex
+	conns map[*websocket.Conn]struct{}
+}
+
+func newConnTracker() *connTracker {
+	return &connTracker{conns: make(map[*websocket.Conn]struct{})}
+}
+
+func (t *connTracker) add(c *websocket.Conn) {
+	t.mu.Lock()
+	t.conns[c] = struct{}{}
+	t.mu.Unlock()
+}
+
+func (t *connTracker) remove(c *websocket.Conn) {
+	t.mu.Lock()
+	delete(t.conns, c)
+	t.mu.Unlock()
+}
+
+// closeAll closes every tracked connection. Each close unblocks the
+// corresponding wsHandler goroutine, which is waiting on a read or write
+// against that same conn.
+func (t *connTracker) closeAll() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for c := range t.conns {
+		c.Close()
+	}
+}
+
+var wsUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	// Gateway sits behind the same origin as its clients in this deployment;
+	// tighten this if /ws is ever exposed cross-origin.
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// wsHandler upgrades the connection and streams hub events to the client.
+// Clients subscribe to the firehose (NewsTopic) plus, optionally, a single
+// news article's comments via ?news_id=. tracker lets main close this
+// connection from the shutdown path even after it's been hijacked.
+func wsHandler(hub *Hub, tracker *connTracker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		requestID := logging.RequestID(r.Context())
+
+		conn, err := wsUpgrader.Upgrade(w, r, nil)
+		if err != nil {
+			log.Printf("[%s] ws upgrade failed: %v", requestID, err)
+			return
+		}
+		defer conn.Close()
+
+		tracker.add(conn)
+		defer tracker.remove(conn)
+
+		topics := []string{NewsTopic}
+		if newsIDStr := getRequestParam(r, "news_id"); newsIDStr != "" {
+			if newsID, err := strconv.Atoi(newsIDStr); err == nil {
+				topics = append(topics, CommentsTopic(newsID))
+			}
+		}
+
+		events := make(chan Event, 32)
+		done := make(chan struct{})
+		defer close(done)
+		var unsubscribes []func()
+		for _, topic := range topics {
+			ch, unsubscribe := hub.Subscribe(topic)
+			unsubscribes = append(unsubscribes, unsubscribe)
+			go forward(ch, events, done)
+		}
+		defer func() {
+			for _, unsubscribe := range unsubscribes {
+				unsubscribe()
+			}
+		}()
+
+		conn.SetReadDeadline(time.Now().Add(wsPongWait))
+		conn.SetPongHandler(func(string) error {
+			conn.SetReadDeadline(time.Now().Add(wsPongWait))
+			return nil
+		})
+
+		// Drain and discard client reads; this endpoint is push-only but we
+		// still need to read so pong frames (and the close handshake) are
+		// processed by gorilla/websocket. closed also fires when tracker
+		// closes conn out from under us during shutdown, since that makes
+		// ReadMessage return an error too.
+		closed := make(chan struct{})
+		go func() {
+			defer close(closed)
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					conn.Close()
+					return
+				}
+			}
+		}()
+
+		ticker := time.NewTicker(wsPingPeriod)
+		defer ticker.Stop()
+
+		log.Printf("[%s] ws client connected: %s", requestID, r.RemoteAddr)
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-closed:
+				return
+			case event, ok := <-events:
+				if !ok {
+					return
+				}
+				conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+				if err := conn.WriteJSON(event); err != nil {
+					log.Printf("[%s] ws write failed: %v", requestID, err)
+					return
+				}
+			case <-ticker.C:
+				conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+				if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+					return
+				}
+			}
+		}
+	}
+}
+
+// forward copies events from a per-topic subscription channel into the
+// connection's single fan-in channel, stopping once the subscription closes
+// or done is closed, whichever comes first.
+func forward(ch chan Event, out chan Event, done <-chan struct{}) {
+	for event := range ch {
+		select {
+		case out <- event:
+		case <-done:
+			return
+		}
+	}
+}
+
+// pollNewsAggregator periodically checks the news aggregator for newly
+// published articles and publishes a news.created event for each one it
+// hasn't seen yet. It runs until ctx is cancelled, which happens as part of
+// the same shutdown path that stops the HTTP server.
+func pollNewsAggregator(ctx context.Context, hub *Hub, interval time.Duration) {
+	seen := make(map[int]struct{})
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, n := range fetchLatestNews() {
+				if _, ok := seen[n.ID]; ok {
+					continue
+				}
+				seen[n.ID] = struct{}{}
+				hub.Publish(NewsTopic, "news.created", n)
+			}
+		}
+	}
+}
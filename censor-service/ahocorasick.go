@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// Match is a single prohibited-word hit, reported with its rune offsets in
+// the (case-folded) input so callers can highlight what tripped the filter.
+type Match struct {
+	Word  string `json:"word"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+}
+
+type node struct {
+	children map[rune]*node
+	fail     *node
+	// output holds every pattern that ends at this node, including ones
+	// inherited through fail links, so a single visit reports all matches.
+	output []string
+}
+
+func newNode() *node {
+	return &node{children: make(map[rune]*node)}
+}
+
+// Matcher is an Aho-Corasick automaton for scanning text for a fixed set of
+// prohibited words in a single O(n) pass over runes.
+type Matcher struct {
+	root     *node
+	boundary bool
+}
+
+// NewMatcher builds the trie and failure links for words, matching each one
+// anywhere it occurs as a substring. Words are matched case-insensitively;
+// callers should not rely on case in the returned Match.Word, which echoes
+// the pattern as given.
+func NewMatcher(words []string) *Matcher {
+	return newMatcher(words, false)
+}
+
+// NewWordBoundaryMatcher is like NewMatcher, but only reports a match when
+// it isn't adjacent to another letter or digit - so "ass" matches in
+// "kick ass" but not in "assume" or "bypass".
+func NewWordBoundaryMatcher(words []string) *Matcher {
+	return newMatcher(words, true)
+}
+
+func newMatcher(words []string, boundary bool) *Matcher {
+	root := newNode()
+
+	for _, word := range words {
+		if word == "" {
+			continue
+		}
+		cur := root
+		for _, r := range strings.ToLower(word) {
+			child, ok := cur.children[r]
+			if !ok {
+				child = newNode()
+				cur.children[r] = child
+			}
+			cur = child
+		}
+		cur.output = append(cur.output, word)
+	}
+
+	// BFS to compute failure links: each node's failure link points to the
+	// longest proper suffix of its path that is also a prefix in the trie.
+	queue := make([]*node, 0, len(root.children))
+	for _, child := range root.children {
+		child.fail = root
+		queue = append(queue, child)
+	}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+
+		for r, child := range cur.children {
+			fail := cur.fail
+			for fail != nil {
+				if next, ok := fail.children[r]; ok {
+					child.fail = next
+					break
+				}
+				fail = fail.fail
+			}
+			if child.fail == nil {
+				child.fail = root
+			}
+			// Inherit output from the failure chain so overlapping
+			// patterns (e.g. "he" and "she") are both reported.
+			child.output = append(child.output, child.fail.output...)
+			queue = append(queue, child)
+		}
+	}
+
+	return &Matcher{root: root, boundary: boundary}
+}
+
+// LoadWordlist reads one prohibited word per line from path, skipping blank
+// lines and lines starting with '#'.
+func LoadWordlist(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var words []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		words = append(words, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return words, nil
+}
+
+// FindAll scans text for every prohibited word, case-folded, and returns
+// each hit with its rune offsets in the (already lower-cased) input.
+func (m *Matcher) FindAll(text string) []Match {
+	text = strings.ToLower(text)
+	runes := []rune(text)
+
+	var matches []Match
+	cur := m.root
+	for i, r := range runes {
+		for cur != m.root {
+			if _, ok := cur.children[r]; ok {
+				break
+			}
+			cur = cur.fail
+		}
+		if next, ok := cur.children[r]; ok {
+			cur = next
+		}
+		for _, word := range cur.output {
+			start := i - len([]rune(strings.ToLower(word))) + 1
+			end := i + 1
+			if m.boundary && !isWordBoundaryMatch(runes, start, end) {
+				continue
+			}
+			matches = append(matches, Match{Word: word, Start: start, End: end})
+		}
+	}
+
+	return matches
+}
+
+// isWordBoundaryMatch reports whether runes[start:end] is not glued to an
+// adjacent letter or digit, so it only matches a whole word rather than a
+// fragment of a longer one.
+func isWordBoundaryMatch(runes []rune, start, end int) bool {
+	if start > 0 && isWordRune(runes[start-1]) {
+		return false
+	}
+	if end < len(runes) && isWordRune(runes[end]) {
+		return false
+	}
+	return true
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestMatcherOverlappingPatterns(t *testing.T) {
+	m := NewMatcher([]string{"he", "she", "his", "hers"})
+
+	matches := m.FindAll("ushers")
+	got := make([]string, len(matches))
+	for i, match := range matches {
+		got[i] = match.Word
+	}
+
+	want := []string{"she", "he", "hers"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("FindAll(%q) matched %v, want %v", "ushers", got, want)
+	}
+}
+
+func TestMatcherCyrillicInput(t *testing.T) {
+	m := NewMatcher([]string{"йцукен"})
+
+	matches := m.FindAll("раскладка ЙЦУКЕН виновата")
+	if len(matches) != 1 || matches[0].Word != "йцукен" {
+		t.Fatalf("FindAll did not match case-folded Cyrillic pattern, got %v", matches)
+	}
+
+	start, end := matches[0].Start, matches[0].End
+	runes := []rune("раскладка йцукен виновата")
+	if string(runes[start:end]) != "йцукен" {
+		t.Fatalf("offsets [%d:%d] do not point at the match in %q", start, end, string(runes))
+	}
+}
+
+func TestMatcherSubstringModeMatchesWithinLongerWord(t *testing.T) {
+	m := NewMatcher([]string{"qwerty"})
+
+	matches := m.FindAll("qwert and qwertyuiop")
+	if len(matches) != 1 {
+		t.Fatalf("expected exactly one match (inside qwertyuiop), got %v", matches)
+	}
+}
+
+func TestWordBoundaryMatcherSkipsPartialWord(t *testing.T) {
+	m := NewWordBoundaryMatcher([]string{"qwerty"})
+
+	matches := m.FindAll("qwert and qwertyuiop and qwerty!")
+	if len(matches) != 1 || matches[0].Start != 25 {
+		t.Fatalf("expected exactly one boundary-respecting match (the standalone word), got %v", matches)
+	}
+}
+
+func TestWordBoundaryMatcherMatchesWholeWordAdjacentToPunctuation(t *testing.T) {
+	m := NewWordBoundaryMatcher([]string{"he"})
+
+	matches := m.FindAll("he, she")
+	got := make([]string, len(matches))
+	for i, match := range matches {
+		got[i] = match.Word
+	}
+
+	want := []string{"he"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("FindAll(%q) matched %v, want %v", "he, she", got, want)
+	}
+}
+
+func TestLoadWordlistSkipsBlankAndCommentLines(t *testing.T) {
+	f := t.TempDir() + "/words.txt"
+	content := "# comment\nqwerty\n\nzxvbnm\n"
+	if err := os.WriteFile(f, []byte(content), 0o644); err != nil {
+		t.Fatalf("os.WriteFile: %v", err)
+	}
+
+	words, err := LoadWordlist(f)
+	if err != nil {
+		t.Fatalf("LoadWordlist: %v", err)
+	}
+
+	want := []string{"qwerty", "zxvbnm"}
+	if !reflect.DeepEqual(words, want) {
+		t.Fatalf("LoadWordlist = %v, want %v", words, want)
+	}
+}
@@ -3,13 +3,18 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"flag"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
 	"time"
 
-	"github.com/google/uuid"
+	"github.com/ilin69mark-hub/APIGateway/internal/logging"
+	"github.com/ilin69mark-hub/APIGateway/internal/middleware"
 )
 
 // Request model for censorship check
@@ -17,54 +22,95 @@ type CheckRequest struct {
 	Text string `json:"text"`
 }
 
-// Middleware for request_id and logging
-func loggingMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		start := time.Now()
-		requestID := r.Header.Get("X-Request-ID")
-		if requestID == "" {
-			requestID = uuid.New().String()
-		}
-		
-		// Log the request
-		log.Printf("[%s] [INFO] [%s] [%s] [%s] [%s] -", 
-			start.Format("2006-01-02 15:04:05"), 
-			requestID, 
-			r.RemoteAddr, 
-			r.Method, 
-			r.URL.Path)
-		
-		// Add request_id to context
-		ctx := context.WithValue(r.Context(), "request_id", requestID)
-		next.ServeHTTP(w, r.WithContext(ctx))
-		
-		// Log the response
-		duration := time.Since(start)
-		// Note: This is a simplified version - in production, you'd need to capture the status code properly
-		log.Printf("[%s] [INFO] [%s] [%s] [%s] [%s] [200] [%v]", 
-			start.Format("2006-01-02 15:04:05"), 
-			requestID, 
-			r.RemoteAddr, 
-			r.Method, 
-			r.URL.Path,
-			duration)
-	})
+// CheckResponse is returned for both passing and rejected text; Matches is
+// empty when the text passed.
+type CheckResponse struct {
+	Message string  `json:"message"`
+	Matches []Match `json:"matches,omitempty"`
+}
+
+// CensorStore owns the live Aho-Corasick automaton and knows how to rebuild
+// it from the wordlist file. It's safe to read (Check) while a reload is in
+// flight from another goroutine.
+type CensorStore struct {
+	path         string
+	wordBoundary bool
+
+	mu      sync.RWMutex
+	matcher *Matcher
 }
 
-func getRequestID(r *http.Request) string {
-	if requestID, ok := r.Context().Value("request_id").(string); ok {
-		return requestID
+// NewCensorStore loads path and builds the initial automaton. When
+// wordBoundary is true, a wordlist entry like "ass" only matches whole
+// words ("kick ass"), not a substring of a longer one ("assume", "bypass").
+func NewCensorStore(path string, wordBoundary bool) (*CensorStore, error) {
+	s := &CensorStore{path: path, wordBoundary: wordBoundary}
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Reload re-reads the wordlist file and swaps in a freshly built automaton.
+// The old automaton continues to serve any Check calls already in flight.
+func (s *CensorStore) Reload() error {
+	words, err := LoadWordlist(s.path)
+	if err != nil {
+		return err
+	}
+
+	var matcher *Matcher
+	if s.wordBoundary {
+		matcher = NewWordBoundaryMatcher(words)
+	} else {
+		matcher = NewMatcher(words)
 	}
-	return ""
+
+	s.mu.Lock()
+	s.matcher = matcher
+	s.mu.Unlock()
+
+	log.Printf("[*] censor wordlist reloaded from %s (%d words)", s.path, len(words))
+	return nil
+}
+
+// Check scans text against the current automaton.
+func (s *CensorStore) Check(text string) []Match {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.matcher.FindAll(text)
 }
 
 func main() {
+	wordsPath := flag.String("censor.words", envOr("CENSOR_WORDS_FILE", "wordlist.txt"), "path to the prohibited-words wordlist")
+	wordBoundary := flag.Bool("censor.word_boundary", envBoolOr("CENSOR_WORD_BOUNDARY", true),
+		"require matches to fall on word boundaries instead of matching any substring")
+	flag.Parse()
+
+	store, err := NewCensorStore(*wordsPath, *wordBoundary)
+	if err != nil {
+		log.Fatalf("failed to load wordlist %q: %v", *wordsPath, err)
+	}
+
+	// Reload the wordlist without restarting the process.
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+	go func() {
+		for range sighup {
+			if err := store.Reload(); err != nil {
+				log.Printf("[!] wordlist reload failed: %v", err)
+			}
+		}
+	}()
+
 	// Create HTTP multiplexer
 	mux := http.NewServeMux()
 
+	limiter := middleware.NewLimiter(middleware.ConfigFromEnv())
+
 	// Register handlers with middleware
 	mux.HandleFunc("/check", func(w http.ResponseWriter, r *http.Request) {
-		loggingMiddleware(http.HandlerFunc(checkHandler)).ServeHTTP(w, r)
+		limiter.Middleware(logging.Middleware(checkHandler(store))).ServeHTTP(w, r)
 	})
 
 	// Create server
@@ -97,83 +143,53 @@ func main() {
 	}
 }
 
-func checkHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
 	}
+	return fallback
+}
 
-	var req CheckRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid JSON", http.StatusBadRequest)
-		return
+func envBoolOr(key string, fallback bool) bool {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
 	}
-
-	// Check if the text contains prohibited words
-	if containsProhibitedWords(req.Text) {
-		http.Error(w, "Text contains prohibited content", http.StatusBadRequest)
-		return
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return fallback
 	}
-
-	// If text passes censorship, return 200 OK
-	w.WriteHeader(http.StatusOK)
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{
-		"message": "Text passed censorship check",
-	})
+	return b
 }
 
-// containsProhibitedWords checks if the text contains any prohibited words
-func containsProhibitedWords(text string) bool {
-	prohibitedWords := []string{"qwerty", "йцукен", "zxvbnm"}
-	
-	for _, word := range prohibitedWords {
-		if containsIgnoreCase(text, word) {
-			return true
+// checkHandler returns the /check handler bound to store.
+func checkHandler(store *CensorStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
 		}
-	}
-	
-	return false
-}
-
-// containsIgnoreCase checks if a string contains another string, ignoring case
-func containsIgnoreCase(s, substr string) bool {
-	s = toLower(s)
-	substr = toLower(substr)
-	return contains(s, substr)
-}
 
-// Simple implementation of string operations to avoid importing strings package
-func toLower(s string) string {
-	var result []byte
-	for i := 0; i < len(s); i++ {
-		c := s[i]
-		if c >= 'A' && c <= 'Z' {
-			c = c + ('a' - 'A')
+		var req CheckRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
 		}
-		result = append(result, c)
-	}
-	return string(result)
-}
 
-func contains(s, substr string) bool {
-	if len(substr) == 0 {
-		return true
-	}
-	if len(substr) > len(s) {
-		return false
-	}
-	for i := 0; i <= len(s)-len(substr); i++ {
-		match := true
-		for j := 0; j < len(substr); j++ {
-			if s[i+j] != substr[j] {
-				match = false
-				break
-			}
-		}
-		if match {
-			return true
+		matches := store.Check(req.Text)
+
+		w.Header().Set("Content-Type", "application/json")
+		if len(matches) > 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(CheckResponse{
+				Message: "Text contains prohibited content",
+				Matches: matches,
+			})
+			return
 		}
+
+		json.NewEncoder(w).Encode(CheckResponse{
+			Message: "Text passed censorship check",
+		})
 	}
-	return false
-}
\ No newline at end of file
+}
@@ -3,22 +3,36 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
 	"strconv"
+	"strings"
 	"time"
 
-	"github.com/google/uuid"
+	"github.com/ilin69mark-hub/APIGateway/comment-service/store"
+	"github.com/ilin69mark-hub/APIGateway/internal/logging"
+	"github.com/ilin69mark-hub/APIGateway/internal/middleware"
 )
 
-// Comment model
+// Comment is the wire representation of a comment. Depth is only populated
+// when a request asks for the flat list format.
 type Comment struct {
 	ID       int    `json:"id"`
 	NewsID   int    `json:"news_id"`
 	ParentID *int   `json:"parent_id,omitempty"`
 	Text     string `json:"text"`
+	Depth    int    `json:"depth,omitempty"`
+}
+
+// CommentNode is a Comment plus its direct replies, used for the tree
+// response format.
+type CommentNode struct {
+	Comment
+	Children []*CommentNode `json:"children,omitempty"`
 }
 
 type CreateCommentRequest struct {
@@ -27,62 +41,46 @@ type CreateCommentRequest struct {
 	Text     string `json:"text"`
 }
 
-// In-memory storage for comments (for demonstration)
-var comments []Comment
-var nextID = 1
-
-// Middleware for request_id and logging
-func loggingMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		start := time.Now()
-		requestID := r.Header.Get("X-Request-ID")
-		if requestID == "" {
-			requestID = uuid.New().String()
-		}
-		
-		// Log the request
-		log.Printf("[%s] [INFO] [%s] [%s] [%s] [%s] -", 
-			start.Format("2006-01-02 15:04:05"), 
-			requestID, 
-			r.RemoteAddr, 
-			r.Method, 
-			r.URL.Path)
-		
-		// Add request_id to context
-		ctx := context.WithValue(r.Context(), "request_id", requestID)
-		next.ServeHTTP(w, r.WithContext(ctx))
-		
-		// Log the response
-		duration := time.Since(start)
-		// Note: This is a simplified version - in production, you'd need to capture the status code properly
-		log.Printf("[%s] [INFO] [%s] [%s] [%s] [%s] [200] [%v]", 
-			start.Format("2006-01-02 15:04:05"), 
-			requestID, 
-			r.RemoteAddr, 
-			r.Method, 
-			r.URL.Path,
-			duration)
-	})
+// ListCommentsResponse is returned by GET /comments: Comments is populated
+// for ?format=flat, Tree otherwise. api-gateway's getCommentsForNews relies
+// on exactly this envelope (it requests &format=flat and decodes
+// {"comments": [...]}); changing either shape here means updating that
+// caller in the same commit. NextCursor is the ID of the last comment in
+// this page, to pass as ?cursor= to fetch the following page; it is 0 only
+// when this page is empty, so a non-zero NextCursor does not mean that a
+// following page has any comments.
+type ListCommentsResponse struct {
+	Comments   []Comment      `json:"comments,omitempty"`
+	Tree       []*CommentNode `json:"tree,omitempty"`
+	NextCursor int            `json:"next_cursor,omitempty"`
 }
 
 func getRequestParam(r *http.Request, key string) string {
 	return r.URL.Query().Get(key)
 }
 
-func getRequestID(r *http.Request) string {
-	if requestID, ok := r.Context().Value("request_id").(string); ok {
-		return requestID
+func main() {
+	driver := flag.String("store.driver", envOr("STORE_DRIVER", "sqlite"), "storage backend: sqlite or postgres")
+	dsn := flag.String("store.dsn", envOr("STORE_DSN", "comments.db"), "sqlite file path or postgres connection string")
+	flag.Parse()
+
+	db, err := openStore(*driver, *dsn)
+	if err != nil {
+		log.Fatalf("failed to open %s store: %v", *driver, err)
 	}
-	return ""
-}
+	defer db.Close()
 
-func main() {
 	// Create HTTP multiplexer
 	mux := http.NewServeMux()
 
+	limiter := middleware.NewLimiter(middleware.ConfigFromEnv())
+
 	// Register handlers with middleware
 	mux.HandleFunc("/comments", func(w http.ResponseWriter, r *http.Request) {
-		loggingMiddleware(http.HandlerFunc(commentsHandler)).ServeHTTP(w, r)
+		limiter.Middleware(logging.Middleware(commentsHandler(db))).ServeHTTP(w, r)
+	})
+	mux.HandleFunc("/comments/", func(w http.ResponseWriter, r *http.Request) {
+		limiter.Middleware(logging.Middleware(commentDetailHandler(db))).ServeHTTP(w, r)
 	})
 
 	// Create server
@@ -115,42 +113,62 @@ func main() {
 	}
 }
 
-func commentsHandler(w http.ResponseWriter, r *http.Request) {
-	switch r.Method {
-	case http.MethodGet:
-		getCommentsHandler(w, r)
-	case http.MethodPost:
-		createCommentHandler(w, r)
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+func openStore(driver, dsn string) (store.Store, error) {
+	switch driver {
+	case "sqlite":
+		return store.NewSQLiteStore(dsn)
+	case "postgres":
+		return store.NewPostgresStore(context.Background(), dsn)
 	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return nil, errors.New("unknown store driver: " + driver)
 	}
 }
 
-func createCommentHandler(w http.ResponseWriter, r *http.Request) {
+func commentsHandler(db store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			getCommentsHandler(db, w, r)
+		case http.MethodPost:
+			createCommentHandler(db, w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+func createCommentHandler(db store.Store, w http.ResponseWriter, r *http.Request) {
 	var req CreateCommentRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid JSON", http.StatusBadRequest)
 		return
 	}
 
-	// Add comment to in-memory storage
-	comment := Comment{
-		ID:       nextID,
+	id, err := db.Create(r.Context(), store.Comment{
 		NewsID:   req.NewsID,
 		ParentID: req.ParentID,
 		Text:     req.Text,
+	})
+	if err != nil {
+		log.Printf("failed to create comment: %v", err)
+		http.Error(w, "Failed to create comment", http.StatusInternalServerError)
+		return
 	}
-	comments = append(comments, comment)
-	nextID++
 
-	// Return the created comment ID
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"id": comment.ID,
+		"id": id,
 	})
 }
 
-func getCommentsHandler(w http.ResponseWriter, r *http.Request) {
+func getCommentsHandler(db store.Store, w http.ResponseWriter, r *http.Request) {
 	newsIDStr := getRequestParam(r, "news_id")
 	if newsIDStr == "" {
 		http.Error(w, "news_id parameter is required", http.StatusBadRequest)
@@ -163,14 +181,118 @@ func getCommentsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Filter comments for the given news ID
-	var filteredComments []Comment
-	for _, comment := range comments {
-		if comment.NewsID == newsID {
-			filteredComments = append(filteredComments, comment)
+	opts := store.ListOpts{}
+	if limitStr := getRequestParam(r, "limit"); limitStr != "" {
+		if limit, err := strconv.Atoi(limitStr); err == nil {
+			opts.Limit = limit
+		}
+	}
+	if cursorStr := getRequestParam(r, "cursor"); cursorStr != "" {
+		if cursor, err := strconv.Atoi(cursorStr); err == nil {
+			opts.Cursor = cursor
 		}
 	}
 
+	stored, err := db.ListByNews(r.Context(), newsID, opts)
+	if err != nil {
+		log.Printf("failed to list comments for news %d: %v", newsID, err)
+		http.Error(w, "Failed to list comments", http.StatusInternalServerError)
+		return
+	}
+
+	comments := make([]Comment, len(stored))
+	for i, c := range stored {
+		comments[i] = Comment{ID: c.ID, NewsID: c.NewsID, ParentID: c.ParentID, Text: c.Text}
+	}
+
+	response := ListCommentsResponse{}
+	if len(comments) > 0 {
+		response.NextCursor = comments[len(comments)-1].ID
+	}
+
+	if getRequestParam(r, "format") == "flat" {
+		response.Comments = withDepth(comments)
+	} else {
+		response.Tree = buildTree(comments)
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(filteredComments)
-}
\ No newline at end of file
+	json.NewEncoder(w).Encode(response)
+}
+
+func commentDetailHandler(db store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		idStr := strings.TrimPrefix(r.URL.Path, "/comments/")
+		if idStr == "" {
+			http.Error(w, "Comment ID is required", http.StatusBadRequest)
+			return
+		}
+
+		id, err := strconv.Atoi(idStr)
+		if err != nil {
+			http.Error(w, "Invalid comment ID", http.StatusBadRequest)
+			return
+		}
+
+		c, err := db.Get(r.Context(), id)
+		if err != nil {
+			http.Error(w, "Comment not found", http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(Comment{ID: c.ID, NewsID: c.NewsID, ParentID: c.ParentID, Text: c.Text})
+	}
+}
+
+// buildTree nests comments by ParentID. Comments whose parent isn't in the
+// page (e.g. it's on a previous page) are returned as roots.
+func buildTree(comments []Comment) []*CommentNode {
+	byID := make(map[int]*CommentNode, len(comments))
+	for _, c := range comments {
+		byID[c.ID] = &CommentNode{Comment: c}
+	}
+
+	var roots []*CommentNode
+	for _, c := range comments {
+		node := byID[c.ID]
+		if c.ParentID != nil {
+			if parent, ok := byID[*c.ParentID]; ok {
+				parent.Children = append(parent.Children, node)
+				continue
+			}
+		}
+		roots = append(roots, node)
+	}
+	return roots
+}
+
+// withDepth returns comments flattened with Depth set to their nesting
+// level, computed by walking each comment's parent chain.
+func withDepth(comments []Comment) []Comment {
+	byID := make(map[int]Comment, len(comments))
+	for _, c := range comments {
+		byID[c.ID] = c
+	}
+
+	out := make([]Comment, len(comments))
+	for i, c := range comments {
+		depth := 0
+		for cur := c; cur.ParentID != nil; {
+			parent, ok := byID[*cur.ParentID]
+			if !ok {
+				break
+			}
+			depth++
+			cur = parent
+		}
+		c.Depth = depth
+		out[i] = c
+	}
+	return out
+}
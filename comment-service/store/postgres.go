@@ -0,0 +1,103 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const postgresSchema = `
+CREATE TABLE IF NOT EXISTS comments (
+	id BIGSERIAL PRIMARY KEY,
+	news_id BIGINT NOT NULL,
+	parent_id BIGINT,
+	text TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_comments_news_id ON comments(news_id);
+`
+
+// PostgresStore is the Store implementation backed by Postgres via pgx.
+type PostgresStore struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresStore connects to connString and applies the comments schema.
+func NewPostgresStore(ctx context.Context, connString string) (*PostgresStore, error) {
+	pool, err := pgxpool.New(ctx, connString)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := pool.Exec(ctx, postgresSchema); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("migrate postgres schema: %w", err)
+	}
+
+	return &PostgresStore{pool: pool}, nil
+}
+
+// Create inserts comment inside its own transaction and returns the new ID.
+func (s *PostgresStore) Create(ctx context.Context, comment Comment) (int, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	var id int
+	err = tx.QueryRow(ctx,
+		`INSERT INTO comments (news_id, parent_id, text) VALUES ($1, $2, $3) RETURNING id`,
+		comment.NewsID, comment.ParentID, comment.Text).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// ListByNews returns comments for newsID ordered by ID, keyset-paginated
+// after opts.Cursor.
+func (s *PostgresStore) ListByNews(ctx context.Context, newsID int, opts ListOpts) ([]Comment, error) {
+	rows, err := s.pool.Query(ctx,
+		`SELECT id, news_id, parent_id, text FROM comments
+		 WHERE news_id = $1 AND id > $2
+		 ORDER BY id LIMIT $3`,
+		newsID, opts.Cursor, clampLimit(opts.Limit))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var comments []Comment
+	for rows.Next() {
+		var c Comment
+		if err := rows.Scan(&c.ID, &c.NewsID, &c.ParentID, &c.Text); err != nil {
+			return nil, err
+		}
+		comments = append(comments, c)
+	}
+	return comments, rows.Err()
+}
+
+// Get returns a single comment by ID.
+func (s *PostgresStore) Get(ctx context.Context, id int) (Comment, error) {
+	var c Comment
+	err := s.pool.QueryRow(ctx,
+		`SELECT id, news_id, parent_id, text FROM comments WHERE id = $1`, id).
+		Scan(&c.ID, &c.NewsID, &c.ParentID, &c.Text)
+	if err != nil {
+		return Comment{}, err
+	}
+	return c, nil
+}
+
+// Close releases the connection pool.
+func (s *PostgresStore) Close() error {
+	s.pool.Close()
+	return nil
+}
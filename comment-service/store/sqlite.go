@@ -0,0 +1,118 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	_ "modernc.org/sqlite"
+)
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS comments (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	news_id INTEGER NOT NULL,
+	parent_id INTEGER,
+	text TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_comments_news_id ON comments(news_id);
+`
+
+// SQLiteStore is the default, embedded Store implementation.
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteStore opens (creating if necessary) the SQLite database at
+// dataSourceName and applies the comments schema.
+func NewSQLiteStore(dataSourceName string) (*SQLiteStore, error) {
+	db, err := sql.Open("sqlite", dataSourceName)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("migrate sqlite schema: %w", err)
+	}
+
+	return &SQLiteStore{db: db}, nil
+}
+
+// Create inserts comment inside its own transaction and returns the new ID.
+func (s *SQLiteStore) Create(ctx context.Context, comment Comment) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx,
+		`INSERT INTO comments (news_id, parent_id, text) VALUES (?, ?, ?)`,
+		comment.NewsID, comment.ParentID, comment.Text)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+// ListByNews returns comments for newsID ordered by ID, keyset-paginated
+// after opts.Cursor.
+func (s *SQLiteStore) ListByNews(ctx context.Context, newsID int, opts ListOpts) ([]Comment, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, news_id, parent_id, text FROM comments
+		 WHERE news_id = ? AND id > ?
+		 ORDER BY id LIMIT ?`,
+		newsID, opts.Cursor, clampLimit(opts.Limit))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var comments []Comment
+	for rows.Next() {
+		var c Comment
+		var parentID sql.NullInt64
+		if err := rows.Scan(&c.ID, &c.NewsID, &parentID, &c.Text); err != nil {
+			return nil, err
+		}
+		if parentID.Valid {
+			v := int(parentID.Int64)
+			c.ParentID = &v
+		}
+		comments = append(comments, c)
+	}
+	return comments, rows.Err()
+}
+
+// Get returns a single comment by ID.
+func (s *SQLiteStore) Get(ctx context.Context, id int) (Comment, error) {
+	var c Comment
+	var parentID sql.NullInt64
+	err := s.db.QueryRowContext(ctx,
+		`SELECT id, news_id, parent_id, text FROM comments WHERE id = ?`, id).
+		Scan(&c.ID, &c.NewsID, &parentID, &c.Text)
+	if err != nil {
+		return Comment{}, err
+	}
+	if parentID.Valid {
+		v := int(parentID.Int64)
+		c.ParentID = &v
+	}
+	return c, nil
+}
+
+// Close closes the underlying database handle.
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,46 @@
+// Package store holds the CommentService persistence layer. Comment is
+// deliberately the only data this package knows about, independent of the
+// transport-level Comment type the HTTP handlers in comment-service use.
+package store
+
+import "context"
+
+// Comment is a single persisted comment.
+type Comment struct {
+	ID       int
+	NewsID   int
+	ParentID *int
+	Text     string
+}
+
+// ListOpts controls keyset pagination for ListByNews.
+type ListOpts struct {
+	// Limit caps the number of comments returned; implementations clamp it
+	// to a sane default/max when unset or out of range.
+	Limit int
+	// Cursor is the last ID seen by the caller; only comments with a
+	// greater ID are returned. Zero starts from the beginning.
+	Cursor int
+}
+
+// Store is the persistence interface CommentService's handlers depend on.
+// SQLiteStore and PostgresStore both implement it.
+type Store interface {
+	Create(ctx context.Context, comment Comment) (int, error)
+	ListByNews(ctx context.Context, newsID int, opts ListOpts) ([]Comment, error)
+	Get(ctx context.Context, id int) (Comment, error)
+	Close() error
+}
+
+const defaultListLimit = 50
+const maxListLimit = 200
+
+func clampLimit(limit int) int {
+	if limit <= 0 {
+		return defaultListLimit
+	}
+	if limit > maxListLimit {
+		return maxListLimit
+	}
+	return limit
+}
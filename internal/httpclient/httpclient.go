@@ -0,0 +1,258 @@
+// Package httpclient wraps http.Client with the resilience the gateway
+// needs for its cross-service calls: a per-target circuit breaker, retry
+// with exponential backoff and full jitter on idempotent verbs, context
+// propagation so a disconnected client aborts the in-flight upstream call,
+// and automatic X-Request-ID forwarding. It also exposes Prometheus
+// counters so upstream health is visible without reading logs.
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/ilin69mark-hub/APIGateway/internal/logging"
+)
+
+var (
+	requestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "upstream_requests_total",
+		Help: "Total upstream HTTP requests, labeled by target service and outcome status.",
+	}, []string{"service", "status"})
+
+	circuitState = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "upstream_circuit_state",
+		Help: "Circuit breaker state per service (0=closed, 1=half_open, 2=open).",
+	}, []string{"service"})
+)
+
+func init() {
+	prometheus.MustRegister(requestsTotal, circuitState)
+}
+
+const (
+	defaultMaxAttempts = 3
+	defaultBaseDelay   = 50 * time.Millisecond
+	defaultMaxDelay    = 2 * time.Second
+
+	defaultFailureThreshold = 5
+	defaultOpenDuration     = 30 * time.Second
+)
+
+// Request describes a single cross-service call. Body is re-sent on every
+// retry attempt, so it must be the fully-built payload rather than a
+// one-shot reader.
+type Request struct {
+	Method  string
+	URL     string
+	Body    []byte
+	Headers http.Header
+}
+
+// Client is a resilient wrapper around http.Client for calls to a single
+// upstream service, identified by name in logs and Prometheus labels.
+type Client struct {
+	name string
+	http *http.Client
+	cb   *circuitBreaker
+}
+
+// New returns a Client for the named upstream service with the package's
+// default timeout, retry and circuit-breaker settings.
+func New(name string) *Client {
+	circuitState.WithLabelValues(name).Set(0)
+	return &Client{
+		name: name,
+		http: &http.Client{Timeout: 10 * time.Second},
+		cb:   newCircuitBreaker(defaultFailureThreshold, defaultOpenDuration),
+	}
+}
+
+// Do executes req, making up to three attempts in total for idempotent verbs
+// (GET, HEAD, OPTIONS, PUT, DELETE) with exponential backoff and full jitter
+// between them. It refuses to call out at all while the circuit is open,
+// including once a failed attempt has opened it mid-retry, and aborts
+// immediately if ctx is cancelled - including mid-retry.
+func (c *Client) Do(ctx context.Context, req Request) (*http.Response, error) {
+	if !c.cb.allow() {
+		circuitState.WithLabelValues(c.name).Set(float64(c.cb.state()))
+		requestsTotal.WithLabelValues(c.name, "circuit_open").Inc()
+		return nil, fmt.Errorf("%s: circuit breaker open", c.name)
+	}
+
+	attempts := 1
+	if isIdempotent(req.Method) {
+		attempts = defaultMaxAttempts
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			if err := sleepWithJitter(ctx, defaultBaseDelay, defaultMaxDelay, attempt); err != nil {
+				return nil, err
+			}
+		}
+
+		resp, err := c.doOnce(ctx, req)
+		if err == nil && resp.StatusCode < 500 {
+			c.cb.recordSuccess()
+			circuitState.WithLabelValues(c.name).Set(float64(c.cb.state()))
+			requestsTotal.WithLabelValues(c.name, strconv.Itoa(resp.StatusCode)).Inc()
+			return resp, nil
+		}
+
+		if err == nil {
+			// Reachable but unhealthy: a 5xx counts against the circuit the
+			// same as a transport error, and is retried the same way.
+			lastErr = fmt.Errorf("server error: %d", resp.StatusCode)
+			requestsTotal.WithLabelValues(c.name, strconv.Itoa(resp.StatusCode)).Inc()
+			resp.Body.Close()
+		} else {
+			lastErr = err
+			requestsTotal.WithLabelValues(c.name, "error").Inc()
+		}
+		c.cb.recordFailure()
+		state := c.cb.state()
+		circuitState.WithLabelValues(c.name).Set(float64(state))
+
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if state == cbOpen {
+			return nil, fmt.Errorf("%s: circuit breaker opened after %d attempt(s): %w", c.name, attempt+1, lastErr)
+		}
+	}
+
+	return nil, fmt.Errorf("%s: request failed after %d attempt(s): %w", c.name, attempts, lastErr)
+}
+
+func (c *Client) doOnce(ctx context.Context, req Request) (*http.Response, error) {
+	var body io.Reader
+	if req.Body != nil {
+		body = bytes.NewReader(req.Body)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, req.Method, req.URL, body)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range req.Headers {
+		for _, v := range values {
+			httpReq.Header.Add(key, v)
+		}
+	}
+	httpReq.Header.Set("X-Request-ID", logging.RequestID(ctx))
+
+	return c.http.Do(httpReq)
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// sleepWithJitter waits a random duration in [0, min(cap, base*2^(attempt-1))]
+// (full jitter), returning early with ctx.Err() if ctx is cancelled first.
+func sleepWithJitter(ctx context.Context, base, maxDelay time.Duration, attempt int) error {
+	backoff := base * time.Duration(int64(1)<<uint(attempt-1))
+	if backoff <= 0 || backoff > maxDelay {
+		backoff = maxDelay
+	}
+
+	delay := time.Duration(rand.Int63n(int64(backoff) + 1))
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+type cbState int
+
+const (
+	cbClosed cbState = iota
+	cbHalfOpen
+	cbOpen
+)
+
+// circuitBreaker is a simple consecutive-failure breaker: closed while
+// failures stay below the threshold, open for openDuration once they don't,
+// then half-open to let a single probe request decide whether to close
+// again or reopen.
+type circuitBreaker struct {
+	mu sync.Mutex
+
+	failureThreshold int
+	openDuration     time.Duration
+
+	st               cbState
+	consecutiveFails int
+	openedAt         time.Time
+}
+
+func newCircuitBreaker(failureThreshold int, openDuration time.Duration) *circuitBreaker {
+	return &circuitBreaker{failureThreshold: failureThreshold, openDuration: openDuration}
+}
+
+// allow reports whether a call may proceed. Only the single caller that
+// flips the breaker from open to half-open is admitted as the probe; every
+// other caller sees it as still open until that probe's outcome is
+// recorded, so a crowd of concurrent requests can't all hit a
+// still-recovering upstream at once.
+func (cb *circuitBreaker) allow() bool {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	switch cb.st {
+	case cbOpen:
+		if time.Since(cb.openedAt) < cb.openDuration {
+			return false
+		}
+		cb.st = cbHalfOpen
+		return true
+	case cbHalfOpen:
+		return false
+	default:
+		return true
+	}
+}
+
+func (cb *circuitBreaker) recordSuccess() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.consecutiveFails = 0
+	cb.st = cbClosed
+}
+
+func (cb *circuitBreaker) recordFailure() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.consecutiveFails++
+	if cb.st == cbHalfOpen || cb.consecutiveFails >= cb.failureThreshold {
+		cb.st = cbOpen
+		cb.openedAt = time.Now()
+	}
+}
+
+func (cb *circuitBreaker) state() cbState {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	return cb.st
+}
@@ -0,0 +1,158 @@
+// Package logging provides the structured request logging middleware
+// shared by the gateway, CommentService and CensorService binaries: a
+// status-capturing ResponseWriter, a private request-ID context key, and a
+// slot for handlers to record per-upstream-call timings that land in the
+// same JSON log line as the request that triggered them.
+package logging
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Logger is the shared JSON structured logger. Fields: ts, level, msg, plus
+// whatever attrs Middleware and RecordUpstream attach.
+var Logger = slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+	ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+		if a.Key == slog.TimeKey {
+			a.Key = "ts"
+		}
+		return a
+	},
+}))
+
+type requestIDKeyType struct{}
+
+var requestIDKey = requestIDKeyType{}
+
+// WithRequestID attaches id to ctx under a private key, avoiding the
+// collisions a string key like "request_id" would risk with other packages.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// RequestID returns the request ID stored by Middleware, or "" if ctx wasn't
+// produced by it.
+func RequestID(ctx context.Context) string {
+	if id, ok := ctx.Value(requestIDKey).(string); ok {
+		return id
+	}
+	return ""
+}
+
+type timingsKeyType struct{}
+
+var timingsKey = timingsKeyType{}
+
+type timings struct {
+	mu sync.Mutex
+	ms map[string]int64
+}
+
+func withTimings(ctx context.Context) context.Context {
+	return context.WithValue(ctx, timingsKey, &timings{ms: make(map[string]int64)})
+}
+
+// RecordUpstream records how long an upstream call named name took, to be
+// logged as "upstream.<name>_ms" on the current request's single log line.
+// It's a no-op if ctx wasn't produced by Middleware.
+func RecordUpstream(ctx context.Context, name string, d time.Duration) {
+	t, ok := ctx.Value(timingsKey).(*timings)
+	if !ok {
+		return
+	}
+	t.mu.Lock()
+	t.ms[name] = d.Milliseconds()
+	t.mu.Unlock()
+}
+
+func timingsAttrs(ctx context.Context) []any {
+	t, ok := ctx.Value(timingsKey).(*timings)
+	if !ok {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	attrs := make([]any, 0, len(t.ms))
+	for name, ms := range t.ms {
+		attrs = append(attrs, slog.Int64("upstream."+name+"_ms", ms))
+	}
+	return attrs
+}
+
+// responseRecorder wraps http.ResponseWriter to capture the status code and
+// byte count actually written, so Middleware can log what was sent instead
+// of assuming 200.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func newResponseRecorder(w http.ResponseWriter) *responseRecorder {
+	return &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+}
+
+func (r *responseRecorder) WriteHeader(code int) {
+	r.status = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += n
+	return n, err
+}
+
+// Hijack lets responseRecorder satisfy http.Hijacker by delegating to the
+// wrapped ResponseWriter, so handlers that take over the connection (like
+// gorilla/websocket's Upgrade) still work when logged through Middleware.
+func (r *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := r.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("logging: underlying ResponseWriter does not support Hijack")
+	}
+	return hijacker.Hijack()
+}
+
+// Middleware extracts or generates the request ID, stashes it and a
+// timings slot on the request context, and emits one structured JSON log
+// line per request once next has returned.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		requestID := r.Header.Get("X-Request-ID")
+		if requestID == "" {
+			requestID = uuid.New().String()
+		}
+
+		ctx := WithRequestID(r.Context(), requestID)
+		ctx = withTimings(ctx)
+
+		rec := newResponseRecorder(w)
+		next.ServeHTTP(rec, r.WithContext(ctx))
+
+		attrs := []any{
+			slog.String("request_id", requestID),
+			slog.String("remote_addr", r.RemoteAddr),
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", rec.status),
+			slog.Int64("duration_ms", time.Since(start).Milliseconds()),
+			slog.Int("bytes", rec.bytes),
+		}
+		attrs = append(attrs, timingsAttrs(ctx)...)
+
+		Logger.Info("request", attrs...)
+	})
+}
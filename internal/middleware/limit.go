@@ -0,0 +1,190 @@
+// Package middleware holds HTTP middleware shared across the gateway,
+// CommentService and CensorService binaries.
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Config controls Limiter's behaviour. Zero values fall back to the
+// defaults applied by ConfigFromEnv.
+type Config struct {
+	// MaxInFlight is the global cap on concurrent non-long-running requests.
+	MaxInFlight int
+	// RatePerSecond and RateBurst configure the per-IP token bucket.
+	RatePerSecond float64
+	RateBurst     int
+	// VisitorTTL is how long an idle per-IP bucket is kept before it's
+	// garbage collected.
+	VisitorTTL time.Duration
+	// LongRunningRequestRE matches request paths that are exempt from the
+	// in-flight cap (e.g. the /ws streaming endpoint) but are still logged.
+	LongRunningRequestRE *regexp.Regexp
+}
+
+const (
+	defaultMaxInFlight   = 256
+	defaultRatePerSecond = 10
+	defaultRateBurst     = 20
+	defaultVisitorTTL    = 5 * time.Minute
+)
+
+// ConfigFromEnv builds a Config from environment variables, falling back to
+// sane defaults for anything unset or invalid:
+//
+//	GATEWAY_MAX_IN_FLIGHT        int     (default 256)
+//	GATEWAY_RATE_PER_SECOND      float64 (default 10)
+//	GATEWAY_RATE_BURST           int     (default 20)
+//	GATEWAY_VISITOR_TTL          Go duration string (default "5m")
+//	GATEWAY_LONG_RUNNING_RE      regexp (default none)
+func ConfigFromEnv() Config {
+	cfg := Config{
+		MaxInFlight:   defaultMaxInFlight,
+		RatePerSecond: defaultRatePerSecond,
+		RateBurst:     defaultRateBurst,
+		VisitorTTL:    defaultVisitorTTL,
+	}
+
+	if v := os.Getenv("GATEWAY_MAX_IN_FLIGHT"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			cfg.MaxInFlight = n
+		}
+	}
+	if v := os.Getenv("GATEWAY_RATE_PER_SECOND"); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil && f > 0 {
+			cfg.RatePerSecond = f
+		}
+	}
+	if v := os.Getenv("GATEWAY_RATE_BURST"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			cfg.RateBurst = n
+		}
+	}
+	if v := os.Getenv("GATEWAY_VISITOR_TTL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			cfg.VisitorTTL = d
+		}
+	}
+	if v := os.Getenv("GATEWAY_LONG_RUNNING_RE"); v != "" {
+		if re, err := regexp.Compile(v); err == nil {
+			cfg.LongRunningRequestRE = re
+		}
+	}
+
+	return cfg
+}
+
+type visitor struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// Limiter enforces a global in-flight request cap and a per-IP token-bucket
+// rate limit. Create one with NewLimiter and wrap handlers with Middleware.
+type Limiter struct {
+	cfg Config
+	sem chan struct{}
+
+	mu       sync.Mutex
+	visitors map[string]*visitor
+}
+
+// NewLimiter builds a Limiter from cfg and starts its idle-visitor reaper.
+// The returned Limiter runs the reaper for the lifetime of the process;
+// there is no Close since all three services create exactly one at startup.
+func NewLimiter(cfg Config) *Limiter {
+	if cfg.MaxInFlight == 0 {
+		cfg.MaxInFlight = defaultMaxInFlight
+	}
+	if cfg.RatePerSecond == 0 {
+		cfg.RatePerSecond = defaultRatePerSecond
+	}
+	if cfg.RateBurst == 0 {
+		cfg.RateBurst = defaultRateBurst
+	}
+	if cfg.VisitorTTL == 0 {
+		cfg.VisitorTTL = defaultVisitorTTL
+	}
+
+	l := &Limiter{
+		cfg:      cfg,
+		sem:      make(chan struct{}, cfg.MaxInFlight),
+		visitors: make(map[string]*visitor),
+	}
+	go l.reapIdleVisitors()
+	return l
+}
+
+// Middleware wraps next with the in-flight cap and per-IP rate limit. Paths
+// matching cfg.LongRunningRequestRE skip the in-flight cap entirely (they're
+// expected to hold a slot for a long time, e.g. a WebSocket stream) but
+// still go through the rate limiter.
+func (l *Limiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !l.allowVisitor(r) {
+			http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+			return
+		}
+
+		longRunning := l.cfg.LongRunningRequestRE != nil && l.cfg.LongRunningRequestRE.MatchString(r.URL.Path)
+		if longRunning {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		select {
+		case l.sem <- struct{}{}:
+			defer func() { <-l.sem }()
+			next.ServeHTTP(w, r)
+		default:
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, "Service busy, try again shortly", http.StatusServiceUnavailable)
+		}
+	})
+}
+
+func (l *Limiter) allowVisitor(r *http.Request) bool {
+	ip := clientIP(r)
+
+	l.mu.Lock()
+	v, ok := l.visitors[ip]
+	if !ok {
+		v = &visitor{limiter: rate.NewLimiter(rate.Limit(l.cfg.RatePerSecond), l.cfg.RateBurst)}
+		l.visitors[ip] = v
+	}
+	v.lastSeen = time.Now()
+	l.mu.Unlock()
+
+	return v.limiter.Allow()
+}
+
+func (l *Limiter) reapIdleVisitors() {
+	ticker := time.NewTicker(l.cfg.VisitorTTL)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		cutoff := time.Now().Add(-l.cfg.VisitorTTL)
+		l.mu.Lock()
+		for ip, v := range l.visitors {
+			if v.lastSeen.Before(cutoff) {
+				delete(l.visitors, ip)
+			}
+		}
+		l.mu.Unlock()
+	}
+}
+
+func clientIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}